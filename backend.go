@@ -0,0 +1,43 @@
+package main
+
+import "time"
+
+// Backend watches the filesystem on behalf of a FileWatcher and delivers
+// results through its Events/Errors channels until told to stop.
+type Backend interface {
+	// Run watches until done is closed or the backend hits an error it
+	// cannot recover from, in which case it returns that error.
+	Run(done <-chan struct{}) error
+	// Close releases any resources held by the backend.
+	Close() error
+}
+
+// pollBackend is the original stat-based backend: it walks the watched
+// directories on every tick and diffs against the last known state. It
+// works everywhere, including filesystems that don't support native
+// notifications (NFS, FUSE, etc.), at the cost of latency and CPU.
+type pollBackend struct {
+	fw *FileWatcher
+}
+
+func newPollBackend(fw *FileWatcher) *pollBackend {
+	return &pollBackend{fw: fw}
+}
+
+func (b *pollBackend) Run(done <-chan struct{}) error {
+	ticker := time.NewTicker(b.fw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.fw.checkChanges()
+		case <-done:
+			return nil
+		}
+	}
+}
+
+func (b *pollBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import "time"
+
+// scheduleDebounced buffers ev and (re)starts a quiescence timer for its
+// path. Further events for the same path before the timer fires replace or
+// merge into the buffered one instead of being sent individually, so a
+// burst of writes to one file surfaces as a single event once things settle.
+func (fw *FileWatcher) scheduleDebounced(ev Event) {
+	fw.debounceMu.Lock()
+	defer fw.debounceMu.Unlock()
+
+	if fw.debouncePending == nil {
+		fw.debouncePending = make(map[string]*time.Timer)
+		fw.debounceEvents = make(map[string]Event)
+	}
+
+	merged := ev
+	if prev, ok := fw.debounceEvents[ev.Path]; ok {
+		merged = mergeEvents(prev, ev, fw.CoalesceOps)
+		fw.debouncePending[ev.Path].Stop()
+	}
+	fw.debounceEvents[ev.Path] = merged
+	fw.debouncePending[ev.Path] = time.AfterFunc(fw.DebounceInterval, func() {
+		fw.flushDebounced(ev.Path)
+	})
+}
+
+// flushDebounced sends the buffered event for path, if still pending, once
+// its quiescence timer fires.
+func (fw *FileWatcher) flushDebounced(path string) {
+	fw.debounceMu.Lock()
+	ev, ok := fw.debounceEvents[path]
+	if ok {
+		delete(fw.debounceEvents, path)
+		delete(fw.debouncePending, path)
+	}
+	fw.debounceMu.Unlock()
+
+	if !ok {
+		return
+	}
+	select {
+	case fw.Events <- ev:
+	case <-fw.done:
+	}
+}
+
+// stopDebounce cancels every pending debounce timer, discarding their
+// buffered events, so Close doesn't leave timers trying to send on a
+// channel that's about to be closed.
+func (fw *FileWatcher) stopDebounce() {
+	fw.debounceMu.Lock()
+	defer fw.debounceMu.Unlock()
+
+	for _, t := range fw.debouncePending {
+		t.Stop()
+	}
+	fw.debouncePending = nil
+	fw.debounceEvents = nil
+}
+
+// mergeEvents folds next into prev for a still-pending debounced path. With
+// CoalesceOps, a Create absorbs any subsequent Write/Chmod on the same path
+// within the debounce window, since callers generally only care that the
+// path ended up created, not that it was also written to right after. A
+// Remove or Rename always passes through as-is instead: the path no longer
+// exists (or exists under a different name), which supersedes an earlier
+// Create and must not be reported as one.
+func mergeEvents(prev, next Event, coalesceOps bool) Event {
+	merged := next
+	if !coalesceOps || next.Op&(Remove|Rename) != 0 {
+		return merged
+	}
+	merged.Op = prev.Op | next.Op
+	if merged.Op&Create == Create {
+		merged.Op = Create
+	}
+	return merged
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestMergeEventsCoalescesWriteIntoCreate(t *testing.T) {
+	prev := Event{Path: "/a", Op: Create}
+	next := Event{Path: "/a", Op: Write}
+
+	got := mergeEvents(prev, next, true)
+	if got.Op != Create {
+		t.Errorf("expected Write to coalesce into Create, got %v", got.Op)
+	}
+}
+
+func TestMergeEventsPassesRemoveThroughUnmerged(t *testing.T) {
+	prev := Event{Path: "/a", Op: Create}
+	next := Event{Path: "/a", Op: Remove}
+
+	got := mergeEvents(prev, next, true)
+	if got.Op != Remove {
+		t.Errorf("expected a Remove to supersede the buffered Create instead of being folded into it, got %v", got.Op)
+	}
+}
+
+func TestMergeEventsPassesRenameThroughUnmerged(t *testing.T) {
+	prev := Event{Path: "/a", Op: Create}
+	next := Event{Path: "/a", Op: Rename, OldPath: "/a", NewPath: "/b"}
+
+	got := mergeEvents(prev, next, true)
+	if got.Op != Rename {
+		t.Errorf("expected a Rename to supersede the buffered Create instead of being folded into it, got %v", got.Op)
+	}
+}
+
+func TestMergeEventsWithoutCoalesceOpsKeepsEventsSeparate(t *testing.T) {
+	prev := Event{Path: "/a", Op: Create}
+	next := Event{Path: "/a", Op: Write}
+
+	got := mergeEvents(prev, next, false)
+	if got.Op != Write {
+		t.Errorf("expected next to pass through untouched when CoalesceOps is false, got %v", got.Op)
+	}
+}
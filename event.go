@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+
+// Op describes a set of file operations, modeled as a bitmask so a single
+// Event can report more than one thing happening to a path at once.
+type Op uint32
+
+const (
+	// Create is sent when a path is seen for the first time.
+	Create Op = 1 << iota
+	// Write is sent when a file's size or modification time changes.
+	Write
+	// Chmod is sent when a path's mode bits change.
+	Chmod
+	// Remove is sent when a previously tracked path disappears.
+	Remove
+	// Rename is sent when a path is renamed; see Event.OldPath.
+	Rename
+	// Move is sent when a path is moved across watched directories.
+	Move
+)
+
+// String implements fmt.Stringer, joining the set bits with "|" the same
+// way fsnotify.Op does.
+func (o Op) String() string {
+	if o == 0 {
+		return ""
+	}
+
+	var ops []string
+	if o&Create == Create {
+		ops = append(ops, "CREATE")
+	}
+	if o&Write == Write {
+		ops = append(ops, "WRITE")
+	}
+	if o&Chmod == Chmod {
+		ops = append(ops, "CHMOD")
+	}
+	if o&Remove == Remove {
+		ops = append(ops, "REMOVE")
+	}
+	if o&Rename == Rename {
+		ops = append(ops, "RENAME")
+	}
+	if o&Move == Move {
+		ops = append(ops, "MOVE")
+	}
+
+	out := ops[0]
+	for _, op := range ops[1:] {
+		out += "|" + op
+	}
+	return out
+}
+
+// Event represents a single filesystem change detected for Path.
+type Event struct {
+	Path string
+	Op   Op
+	Info FileInfo
+
+	// OldPath and NewPath are set only on Rename events, correlated from a
+	// matching delete and create within a tick (see FileWatcher.RenameWindow).
+	// Path is equal to NewPath for these events.
+	OldPath string
+	NewPath string
+}
+
+// String implements fmt.Stringer.
+func (e Event) String() string {
+	if e.Op&Rename == Rename && e.OldPath != "" && e.NewPath != "" {
+		return fmt.Sprintf("%q -> %q: %s", e.OldPath, e.NewPath, e.Op)
+	}
+	return fmt.Sprintf("%q: %s", e.Path, e.Op)
+}
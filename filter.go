@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Include restricts watching to paths matching at least one of the given
+// globs. Patterns are matched against the full path, as well as against
+// the path's base name, so a plain "*.go" matches any Go file regardless of
+// directory. A pattern containing "/" is matched segment by segment using
+// filepath.Match rules per segment, with one gitignore-style extension: a
+// "**" segment matches zero or more path segments, so "src/**/foo.go"
+// matches "src/foo.go", "src/a/foo.go", "src/a/b/foo.go", and so on.
+func (fw *FileWatcher) Include(patterns ...string) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	fw.includes = append(fw.includes, patterns...)
+}
+
+// Exclude prevents watching paths matching any of the given globs. Excluded
+// directories are pruned during the walk (via filepath.SkipDir) rather than
+// merely filtered afterwards, so large ignored trees like node_modules or
+// .git are never descended into.
+func (fw *FileWatcher) Exclude(patterns ...string) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	fw.excludes = append(fw.excludes, patterns...)
+}
+
+// excluded reports whether path matches any Exclude pattern.
+func (fw *FileWatcher) excluded(path string) bool {
+	fw.mutex.RLock()
+	defer fw.mutex.RUnlock()
+	return matchesAny(fw.excludes, path)
+}
+
+// included reports whether path should be watched given the current
+// Include patterns. With no Include patterns set, everything is included.
+func (fw *FileWatcher) included(path string) bool {
+	fw.mutex.RLock()
+	defer fw.mutex.RUnlock()
+	if len(fw.includes) == 0 {
+		return true
+	}
+	return matchesAny(fw.includes, path)
+}
+
+// watchable reports whether path passes both the Include and Exclude
+// filters and should be recorded/watched.
+func (fw *FileWatcher) watchable(path string) bool {
+	return !fw.excluded(path) && fw.included(path)
+}
+
+// matchesAny reports whether path, or its base name, matches any of the
+// given globs.
+func matchesAny(patterns []string, path string) bool {
+	base := filepath.Base(path)
+	for _, pattern := range patterns {
+		if matchGlob(pattern, path) {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches pattern against path segment by segment, so a "**"
+// segment can match zero or more path segments the way filepath.Match
+// alone can't (it never crosses "/").
+func matchGlob(pattern, path string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		if matchSegments(pattern[1:], path) {
+			return true
+		}
+		if len(path) == 0 {
+			return false
+		}
+		return matchSegments(pattern, path[1:])
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"src/**/foo.go", "src/foo.go", true},
+		{"src/**/foo.go", "src/a/foo.go", true},
+		{"src/**/foo.go", "src/a/b/foo.go", true},
+		{"src/**/foo.go", "src/a/b/bar.go", false},
+		{"src/**/foo.go", "other/foo.go", false},
+		{"**/*.go", "a/b/c.go", true},
+		{"**/*.go", "c.go", true},
+		{"*.go", "a/b/c.go", false},
+	}
+
+	for _, c := range cases {
+		if got := matchGlob(c.pattern, c.path); got != c.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", c.pattern, c.path, got, c.want)
+		}
+	}
+}
+
+func TestMatchesAnyFallsBackToBaseName(t *testing.T) {
+	if !matchesAny([]string{"*.go"}, "/some/deep/path/main.go") {
+		t.Error("expected *.go to match main.go by base name even without a / in the pattern")
+	}
+	if matchesAny([]string{"*.go"}, "/some/deep/path/main.txt") {
+		t.Error("expected *.go not to match main.txt")
+	}
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -17,6 +18,23 @@ func main() {
 	watcher := NewFileWatcher(os.Args[1:])
 	fmt.Printf("Starting file watcher for directories: %v\n", watcher.directories)
 
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				fmt.Println(event)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("error:", err)
+			}
+		}
+	}()
+
 	if err := watcher.Start(); err != nil {
 		log.Fatal(err)
 	}
@@ -28,6 +46,9 @@ func NewFileWatcher(dirs []string) *FileWatcher {
 		directories: dirs,
 		fileStates:  make(map[string]FileInfo),
 		interval:    time.Second, // Check every second
+		Events:      make(chan Event),
+		Errors:      make(chan error),
+		done:        make(chan struct{}),
 	}
 }
 
@@ -35,8 +56,152 @@ func NewFileWatcher(dirs []string) *FileWatcher {
 type FileWatcher struct {
 	directories []string
 	fileStates  map[string]FileInfo
+	// initialized is set once fileStates holds a real baseline scan, so
+	// Start can skip its own scan when LoadState has already primed one.
+	initialized bool
 	mutex       sync.RWMutex
 	interval    time.Duration
+
+	// Events delivers a value for every filesystem change detected.
+	Events chan Event
+	// Errors delivers non-fatal errors encountered while watching.
+	Errors chan error
+
+	includes []string
+	excludes []string
+
+	// HashMode selects whether checkChanges falls back to hashing file
+	// contents to catch modifications that leave size and mtime
+	// unchanged (e.g. rsync preserving timestamps, touch -r). Defaults to
+	// HashNone.
+	HashMode HashMode
+
+	// RenameWindow lets rename/move correlation span multiple ticks: a
+	// create and delete that don't match within the same tick are kept
+	// pending and retried against later ticks until this much time has
+	// passed, for filesystems slow enough to split a move across checks.
+	RenameWindow time.Duration
+
+	pendingCreates []pendingChange
+	pendingDeletes []pendingChange
+
+	// DebounceInterval, when non-zero, delays delivering an event until no
+	// further event for the same path has arrived for that long, coalescing
+	// bursts (e.g. an editor's write+rename atomic save) into one event.
+	DebounceInterval time.Duration
+	// CoalesceOps additionally merges a Create with any Write/Chmod that
+	// follows it on the same path within DebounceInterval into a single
+	// Create event.
+	CoalesceOps bool
+
+	debounceMu      sync.Mutex
+	debouncePending map[string]*time.Timer
+	debounceEvents  map[string]Event
+
+	backend  Backend
+	done     chan struct{}
+	runWG    sync.WaitGroup
+	closed   bool
+	closeMux sync.Mutex
+}
+
+// Add starts watching path in addition to the watcher's existing directories.
+// It walks path immediately so future ticks have a baseline to diff against,
+// and registers the subtree with the native backend, if one is running, so
+// events for it start arriving right away rather than only once polling
+// happens to rediscover it.
+func (fw *FileWatcher) Add(path string) error {
+	fw.mutex.Lock()
+	fw.directories = append(fw.directories, path)
+	fw.mutex.Unlock()
+
+	if err := fw.recordTree(path); err != nil {
+		return err
+	}
+	return fw.watchTreeNative(path)
+}
+
+// Remove stops watching path and drops any tracked state beneath it,
+// including un-watching it from the native backend so events for the
+// removed subtree stop arriving instead of silently repopulating fileStates.
+func (fw *FileWatcher) Remove(path string) error {
+	fw.mutex.Lock()
+	for i, dir := range fw.directories {
+		if dir == path {
+			fw.directories = append(fw.directories[:i], fw.directories[i+1:]...)
+			break
+		}
+	}
+
+	var dirs []string
+	for p, info := range fw.fileStates {
+		if p == path || strings.HasPrefix(p, path+string(os.PathSeparator)) {
+			if info.IsDir {
+				dirs = append(dirs, p)
+			}
+			delete(fw.fileStates, p)
+		}
+	}
+	backend := fw.backend
+	fw.mutex.Unlock()
+
+	if nb, ok := backend.(*nativeBackend); ok {
+		for _, d := range dirs {
+			nb.watcher.Remove(d)
+		}
+	}
+	return nil
+}
+
+// Close stops the watcher and closes the Events and Errors channels. It
+// waits for the running backend's Run to return first, so no send on
+// Events/Errors can race the channel close. It is safe to call Close more
+// than once.
+func (fw *FileWatcher) Close() error {
+	fw.closeMux.Lock()
+	defer fw.closeMux.Unlock()
+
+	if fw.closed {
+		return nil
+	}
+	fw.closed = true
+	close(fw.done)
+	fw.stopDebounce()
+
+	fw.mutex.Lock()
+	backend := fw.backend
+	fw.mutex.Unlock()
+	if backend != nil {
+		backend.Close()
+	}
+	fw.runWG.Wait()
+
+	close(fw.Events)
+	close(fw.Errors)
+	return nil
+}
+
+// emit sends ev on the Events channel, dropping it instead of blocking
+// forever if the watcher has been closed. If DebounceInterval is set, ev is
+// buffered and coalesced with later events for the same path instead.
+func (fw *FileWatcher) emit(ev Event) {
+	if fw.DebounceInterval > 0 {
+		fw.scheduleDebounced(ev)
+		return
+	}
+	select {
+	case fw.Events <- ev:
+	case <-fw.done:
+	}
+}
+
+// emitErr sends err on the Errors channel, dropping it instead of blocking
+// forever if the watcher has been closed.
+func (fw *FileWatcher) emitErr(err error) {
+	select {
+	case fw.Errors <- err:
+	case <-fw.done:
+	}
 }
 
 // FileInfo stores information about a file
@@ -45,74 +210,126 @@ type FileInfo struct {
 	ModTime time.Time
 	Mode    os.FileMode
 	IsDir   bool
+	// Hash is the content fingerprint computed according to the
+	// FileWatcher's HashMode, or nil when HashMode is HashNone or the path
+	// is a directory.
+	Hash []byte
 }
 
 // getFileInfo retrieves file information
 func getFileInfo(path string) (FileInfo, error) {
+	return getFileInfoHashed(path, HashNone)
+}
+
+// getFileInfoHashed retrieves file information and, for regular files,
+// computes a content fingerprint according to mode.
+func getFileInfoHashed(path string, mode HashMode) (FileInfo, error) {
 	stat, err := os.Stat(path)
 	if err != nil {
 		return FileInfo{}, err
 	}
-	return FileInfo{
+
+	info := FileInfo{
 		Size:    stat.Size(),
 		ModTime: stat.ModTime(),
 		Mode:    stat.Mode(),
 		IsDir:   stat.IsDir(),
-	}, nil
+	}
+
+	if !info.IsDir && mode != HashNone {
+		hash, err := hashFile(path, mode)
+		if err != nil {
+			return FileInfo{}, err
+		}
+		info.Hash = hash
+	}
+
+	return info, nil
 }
 
-// initialize builds the initial state
+// initialize builds the initial state, unless LoadState already primed one.
 func (fw *FileWatcher) initialize() error {
-	for _, dir := range fw.directories {
-		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			fileInfo, err := getFileInfo(path)
-			if err != nil {
-				return err
-			}
-			fw.mutex.Lock()
-			fw.fileStates[path] = fileInfo
-			fw.mutex.Unlock()
-			return nil
-		})
-		if err != nil {
+	fw.mutex.RLock()
+	initialized := fw.initialized
+	fw.mutex.RUnlock()
+	if initialized {
+		return nil
+	}
+
+	fw.mutex.RLock()
+	dirs := append([]string(nil), fw.directories...)
+	fw.mutex.RUnlock()
+
+	for _, dir := range dirs {
+		if err := fw.recordTree(dir); err != nil {
 			return err
 		}
 	}
+
+	fw.mutex.Lock()
+	fw.initialized = true
+	fw.mutex.Unlock()
 	return nil
 }
 
-// Start begins watching the directories
+// Start begins watching the directories. It prefers the native OS
+// notification backend and transparently falls back to polling if the
+// native backend can't be initialized or fails partway through (e.g. an
+// exhausted inotify instance or an unsupported filesystem).
 func (fw *FileWatcher) Start() error {
 	if err := fw.initialize(); err != nil {
 		return err
 	}
 
-	ticker := time.NewTicker(fw.interval)
-	defer ticker.Stop()
+	backend, err := newNativeBackend(fw)
+	if err != nil {
+		fw.emitErr(fmt.Errorf("native backend unavailable, falling back to polling: %w", err))
+		return fw.runBackend(newPollBackend(fw))
+	}
 
-	for range ticker.C {
-		fw.checkChanges()
+	if err := fw.runBackend(backend); err != nil {
+		fw.emitErr(fmt.Errorf("native backend failed, falling back to polling: %w", err))
+		return fw.runBackend(newPollBackend(fw))
 	}
 	return nil
 }
 
+// runBackend runs backend until it stops, tracking it as the watcher's
+// active backend so Close can release it. It holds runWG for the duration
+// of backend.Run, so Close can wait for it (and any emit it's in the
+// middle of) to fully return before closing Events/Errors.
+func (fw *FileWatcher) runBackend(backend Backend) error {
+	fw.mutex.Lock()
+	fw.backend = backend
+	fw.mutex.Unlock()
+
+	fw.runWG.Add(1)
+	defer fw.runWG.Done()
+
+	err := backend.Run(fw.done)
+	backend.Close()
+	return err
+}
+
 // checkChanges looks for file system changes
 func (fw *FileWatcher) checkChanges() {
 	currentFiles := make(map[string]struct{})
+	var creates, deletes []pendingChange
+
+	fw.mutex.RLock()
+	dirs := append([]string(nil), fw.directories...)
+	fw.mutex.RUnlock()
 
 	// Check all directories for changes
-	for _, dir := range fw.directories {
-		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
+	for _, dir := range dirs {
+		fw.walkFiltered(dir, func(path string, info os.FileInfo, walkErr error) error {
+			if walkErr != nil {
 				// File might have been deleted
 				return nil
 			}
 
 			currentFiles[path] = struct{}{}
-			newInfo, err := getFileInfo(path)
+			newInfo, err := getFileInfoHashed(path, fw.HashMode)
 			if err != nil {
 				return nil
 			}
@@ -122,17 +339,21 @@ func (fw *FileWatcher) checkChanges() {
 			fw.mutex.RUnlock()
 
 			if !exists {
-				fmt.Printf("File created: %s\n", path)
+				creates = append(creates, pendingChange{path: path, info: newInfo, seen: time.Now()})
 			} else {
-				// Check for modifications
-				if oldInfo.Size != newInfo.Size {
-					fmt.Printf("File content modified (size changed): %s\n", path)
-				}
-				if oldInfo.ModTime != newInfo.ModTime {
-					fmt.Printf("File modified (time changed): %s\n", path)
+				var op Op
+				if oldInfo.Size != newInfo.Size || oldInfo.ModTime != newInfo.ModTime {
+					op |= Write
+				} else if fw.HashMode != HashNone && oldInfo.Hash != nil && !bytes.Equal(oldInfo.Hash, newInfo.Hash) {
+					// Stat metadata matches, but some editors and sync
+					// tools preserve mtime across real content changes.
+					op |= Write
 				}
 				if oldInfo.Mode != newInfo.Mode {
-					fmt.Printf("File attributes modified: %s\n", path)
+					op |= Chmod
+				}
+				if op != 0 {
+					fw.emit(Event{Path: path, Op: op, Info: newInfo})
 				}
 			}
 
@@ -146,11 +367,22 @@ func (fw *FileWatcher) checkChanges() {
 
 	// Check for deleted files
 	fw.mutex.Lock()
-	for path := range fw.fileStates {
+	for path, info := range fw.fileStates {
 		if _, exists := currentFiles[path]; !exists {
-			fmt.Printf("File deleted: %s\n", path)
+			deletes = append(deletes, pendingChange{path: path, info: info, seen: time.Now()})
 			delete(fw.fileStates, path)
 		}
 	}
 	fw.mutex.Unlock()
+
+	renames, createEvents, deleteEvents := fw.correlateRenames(creates, deletes)
+	for _, ev := range renames {
+		fw.emit(ev)
+	}
+	for _, ev := range createEvents {
+		fw.emit(ev)
+	}
+	for _, ev := range deleteEvents {
+		fw.emit(ev)
+	}
 }
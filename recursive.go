@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WatchRecursive starts watching root and every directory beneath it,
+// including ones created after this call returns. Use Include/Exclude
+// beforehand to scope what gets recorded and watched.
+func (fw *FileWatcher) WatchRecursive(root string) error {
+	fw.mutex.Lock()
+	fw.directories = append(fw.directories, root)
+	fw.mutex.Unlock()
+
+	if err := fw.recordTree(root); err != nil {
+		return err
+	}
+
+	return fw.watchTreeNative(root)
+}
+
+// recordTree walks root, recording FileInfo for every watchable path and
+// pruning excluded directories with filepath.SkipDir so huge ignored trees
+// like node_modules or .git are never descended into.
+func (fw *FileWatcher) recordTree(root string) error {
+	return fw.walkFiltered(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		fileInfo, err := getFileInfoHashed(path, fw.HashMode)
+		if err != nil {
+			return err
+		}
+		fw.mutex.Lock()
+		fw.fileStates[path] = fileInfo
+		fw.mutex.Unlock()
+		return nil
+	})
+}
+
+// walkFiltered walks root, pruning excluded directories with
+// filepath.SkipDir, and calls fn for every path that passes the Include and
+// Exclude filters (fn sees walk errors too, via its err parameter, so each
+// caller can decide whether to propagate or skip them). It centralizes the
+// filtering logic shared by every walk the watcher does (initial scan,
+// Add/WatchRecursive, checkChanges, LoadState).
+func (fw *FileWatcher) walkFiltered(root string, fn func(path string, info os.FileInfo, err error) error) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fn(path, nil, err)
+		}
+		if fw.excluded(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fw.included(path) {
+			return nil
+		}
+		return fn(path, info, nil)
+	})
+}
+
+// watchTreeNative registers root and its subdirectories with the active
+// native backend, if one is running. It is a no-op under pollBackend, which
+// already rediscovers new directories on every tick via filepath.Walk.
+func (fw *FileWatcher) watchTreeNative(root string) error {
+	fw.mutex.RLock()
+	backend, ok := fw.backend.(*nativeBackend)
+	fw.mutex.RUnlock()
+	if !ok {
+		return nil
+	}
+	return backend.addTree(root)
+}
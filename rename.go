@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// pendingChange is a create or delete seen during checkChanges that hasn't
+// yet been matched into a Rename event.
+type pendingChange struct {
+	path string
+	info FileInfo
+	seen time.Time
+}
+
+// renameKey fingerprints a FileInfo so a delete and a create can be
+// correlated as the same file having moved: same size, mtime and mode, and
+// matching content hash when one was computed.
+func renameKey(info FileInfo) string {
+	key := fmt.Sprintf("%d:%d:%s", info.Size, info.ModTime.UnixNano(), info.Mode)
+	if info.Hash != nil {
+		key += ":" + string(info.Hash)
+	}
+	return key
+}
+
+// correlateRenames matches this tick's creates and deletes (plus anything
+// still pending from previous ticks within RenameWindow) by renameKey. A
+// delete/create pair that shares a key uniquely is reported as a single
+// Rename event instead of separate Create/Remove events. Unmatched entries
+// are either carried forward as pending, if RenameWindow allows more time,
+// or returned as plain Create/Remove events.
+func (fw *FileWatcher) correlateRenames(newCreates, newDeletes []pendingChange) (renames, creates, deletes []Event) {
+	fw.mutex.Lock()
+	allCreates := append(fw.pendingCreates, newCreates...)
+	allDeletes := append(fw.pendingDeletes, newDeletes...)
+	fw.mutex.Unlock()
+
+	byKey := make(map[string][]int, len(allDeletes))
+	for i, d := range allDeletes {
+		k := renameKey(d.info)
+		byKey[k] = append(byKey[k], i)
+	}
+
+	deleteUsed := make([]bool, len(allDeletes))
+	createUsed := make([]bool, len(allCreates))
+
+	for ci, c := range allCreates {
+		idxs := byKey[renameKey(c.info)]
+		if len(idxs) != 1 {
+			// No candidate, or more than one possible match: too
+			// ambiguous to correlate, fall through to plain events.
+			continue
+		}
+		di := idxs[0]
+		if deleteUsed[di] {
+			continue
+		}
+		deleteUsed[di] = true
+		createUsed[ci] = true
+		renames = append(renames, Event{
+			Path:    c.path,
+			Op:      Rename,
+			Info:    c.info,
+			OldPath: allDeletes[di].path,
+			NewPath: c.path,
+		})
+	}
+
+	var stillPendingCreates, stillPendingDeletes []pendingChange
+	for ci, c := range allCreates {
+		if createUsed[ci] {
+			continue
+		}
+		if fw.RenameWindow > 0 && time.Since(c.seen) < fw.RenameWindow {
+			stillPendingCreates = append(stillPendingCreates, c)
+			continue
+		}
+		creates = append(creates, Event{Path: c.path, Op: Create, Info: c.info})
+	}
+	for di, d := range allDeletes {
+		if deleteUsed[di] {
+			continue
+		}
+		if fw.RenameWindow > 0 && time.Since(d.seen) < fw.RenameWindow {
+			stillPendingDeletes = append(stillPendingDeletes, d)
+			continue
+		}
+		deletes = append(deletes, Event{Path: d.path, Op: Remove, Info: d.info})
+	}
+
+	fw.mutex.Lock()
+	fw.pendingCreates = stillPendingCreates
+	fw.pendingDeletes = stillPendingDeletes
+	fw.mutex.Unlock()
+
+	return renames, creates, deletes
+}
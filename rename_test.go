@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCorrelateRenamesAmbiguousDuplicateKeys(t *testing.T) {
+	fw := NewFileWatcher(nil)
+
+	info := FileInfo{Size: 10, ModTime: time.Unix(1000, 0)}
+	deletes := []pendingChange{
+		{path: "/a/old1", info: info, seen: time.Now()},
+		{path: "/a/old2", info: info, seen: time.Now()},
+	}
+	creates := []pendingChange{
+		{path: "/a/new", info: info, seen: time.Now()},
+	}
+
+	renames, createEvents, deleteEvents := fw.correlateRenames(creates, deletes)
+
+	if len(renames) != 0 {
+		t.Fatalf("expected no renames when a create matches two identical deletes, got %d", len(renames))
+	}
+	if len(createEvents) != 1 || createEvents[0].Path != "/a/new" {
+		t.Fatalf("expected the create to fall through as a plain Create event, got %+v", createEvents)
+	}
+	if len(deleteEvents) != 2 {
+		t.Fatalf("expected both ambiguous deletes to fall through as plain Remove events, got %d", len(deleteEvents))
+	}
+}
+
+func TestCorrelateRenamesWindowCarryover(t *testing.T) {
+	fw := NewFileWatcher(nil)
+	fw.RenameWindow = time.Minute
+
+	info := FileInfo{Size: 42, ModTime: time.Unix(2000, 0)}
+
+	// Tick 1: only the delete side of the move has been observed so far, so
+	// it should be held pending rather than reported as a plain Remove.
+	renames, creates, deletes := fw.correlateRenames(nil, []pendingChange{
+		{path: "/a/old", info: info, seen: time.Now()},
+	})
+	if len(renames) != 0 || len(creates) != 0 || len(deletes) != 0 {
+		t.Fatalf("expected the lone delete to be held pending within RenameWindow, got renames=%d creates=%d deletes=%d",
+			len(renames), len(creates), len(deletes))
+	}
+	if len(fw.pendingDeletes) != 1 {
+		t.Fatalf("expected the delete to be carried forward as pending, got %d pending", len(fw.pendingDeletes))
+	}
+
+	// Tick 2: the matching create shows up and should correlate against the
+	// delete carried over from tick 1.
+	renames, creates, deletes = fw.correlateRenames([]pendingChange{
+		{path: "/a/new", info: info, seen: time.Now()},
+	}, nil)
+	if len(renames) != 1 {
+		t.Fatalf("expected the carried-over delete to correlate with the new create, got %d renames", len(renames))
+	}
+	if renames[0].OldPath != "/a/old" || renames[0].NewPath != "/a/new" {
+		t.Fatalf("unexpected rename event: %+v", renames[0])
+	}
+	if len(creates) != 0 || len(deletes) != 0 {
+		t.Fatalf("a matched pair should not also appear as plain events, got creates=%d deletes=%d", len(creates), len(deletes))
+	}
+}
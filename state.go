@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// stateVersion guards against loading a snapshot written by an incompatible
+// future format.
+const stateVersion = 1
+
+// stateSnapshot is the on-disk representation of FileWatcher.fileStates.
+type stateSnapshot struct {
+	Version int                 `json:"version"`
+	Files   map[string]FileInfo `json:"files"`
+}
+
+// SaveState serializes the watcher's current view of the filesystem to
+// path, so a future LoadState can pick up where this run left off.
+func (fw *FileWatcher) SaveState(path string) error {
+	fw.mutex.RLock()
+	snapshot := stateSnapshot{
+		Version: stateVersion,
+		Files:   make(map[string]FileInfo, len(fw.fileStates)),
+	}
+	for p, info := range fw.fileStates {
+		snapshot.Files[p] = info
+	}
+	fw.mutex.RUnlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename into place so a crash mid-write never
+	// leaves LoadState a truncated file to choke on; rename is atomic on
+	// the same filesystem, which the temp file is by construction.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadState reads a snapshot previously written by SaveState and diffs it
+// against the current on-disk state of fw.directories, emitting
+// Create/Write/Remove events for everything that changed while the watcher
+// was down. The caller must already be reading from Events/Errors before
+// calling this (the same requirement Start has), since it's unbuffered and
+// LoadState emits synchronously. Call it before Start: it primes
+// fileStates itself, so Start's own initial scan becomes a no-op rescan.
+// A missing path is treated as a fresh start, not an error.
+func (fw *FileWatcher) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot stateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	if snapshot.Version != stateVersion {
+		return fmt.Errorf("filewatcher: state file %s has unsupported version %d", path, snapshot.Version)
+	}
+
+	current := make(map[string]FileInfo)
+	for _, dir := range fw.directories {
+		err := fw.walkFiltered(dir, func(p string, info os.FileInfo, walkErr error) error {
+			if walkErr != nil {
+				return nil
+			}
+			fileInfo, err := getFileInfoHashed(p, fw.HashMode)
+			if err != nil {
+				return nil
+			}
+			current[p] = fileInfo
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for p, newInfo := range current {
+		oldInfo, existed := snapshot.Files[p]
+		if !existed {
+			fw.emit(Event{Path: p, Op: Create, Info: newInfo})
+			continue
+		}
+		var op Op
+		if oldInfo.Size != newInfo.Size || !oldInfo.ModTime.Equal(newInfo.ModTime) {
+			op |= Write
+		} else if fw.HashMode != HashNone && oldInfo.Hash != nil && !bytes.Equal(oldInfo.Hash, newInfo.Hash) {
+			op |= Write
+		}
+		if oldInfo.Mode != newInfo.Mode {
+			op |= Chmod
+		}
+		if op != 0 {
+			fw.emit(Event{Path: p, Op: op, Info: newInfo})
+		}
+	}
+	for p, oldInfo := range snapshot.Files {
+		if _, exists := current[p]; !exists {
+			fw.emit(Event{Path: p, Op: Remove, Info: oldInfo})
+		}
+	}
+
+	fw.mutex.Lock()
+	fw.fileStates = current
+	fw.initialized = true
+	fw.mutex.Unlock()
+
+	return nil
+}
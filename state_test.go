@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadStateDiffsAgainstSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	mustWrite := func(name, contents string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	unchanged := mustWrite("unchanged.txt", "same")
+	changed := mustWrite("changed.txt", "before")
+	removed := mustWrite("removed.txt", "gone soon")
+
+	fw := NewFileWatcher([]string{dir})
+	if err := fw.initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := fw.SaveState(statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mutate the tree while the watcher is "down": modify one file, delete
+	// another, leave one alone, and create a new one.
+	if err := os.WriteFile(changed, []byte("after, much longer than before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	created := mustWrite("created.txt", "brand new")
+
+	fw2 := NewFileWatcher([]string{dir})
+	events := make(chan Event, 16)
+	go func() {
+		for ev := range fw2.Events {
+			events <- ev
+		}
+		close(events)
+	}()
+
+	if err := fw2.LoadState(statePath); err != nil {
+		t.Fatal(err)
+	}
+	fw2.Close()
+
+	got := make(map[string]Op)
+	for ev := range events {
+		got[ev.Path] = ev.Op
+	}
+
+	if op, ok := got[changed]; !ok || op&Write == 0 {
+		t.Errorf("expected a Write event for the modified file, got %v (seen=%v)", op, ok)
+	}
+	if op, ok := got[removed]; !ok || op&Remove == 0 {
+		t.Errorf("expected a Remove event for the deleted file, got %v (seen=%v)", op, ok)
+	}
+	if op, ok := got[created]; !ok || op&Create == 0 {
+		t.Errorf("expected a Create event for the new file, got %v (seen=%v)", op, ok)
+	}
+	if op, ok := got[unchanged]; ok {
+		t.Errorf("unchanged file should not have produced an event, got %v", op)
+	}
+}
+
+func TestLoadStateMissingFileIsNotAnError(t *testing.T) {
+	fw := NewFileWatcher([]string{t.TempDir()})
+	go func() {
+		for range fw.Events {
+		}
+	}()
+	defer fw.Close()
+
+	if err := fw.LoadState(filepath.Join(t.TempDir(), "does-not-exist.json")); err != nil {
+		t.Fatalf("expected a missing state file to be treated as a fresh start, got error: %v", err)
+	}
+}